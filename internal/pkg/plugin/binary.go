@@ -6,6 +6,8 @@
 package plugin
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -14,6 +16,7 @@ import (
 	"plugin"
 
 	"github.com/sylabs/sif/pkg/sif"
+	"github.com/sylabs/singularity/internal/pkg/plugin/blobstore"
 	"github.com/sylabs/singularity/internal/pkg/util/fs"
 	pluginapi "github.com/sylabs/singularity/pkg/plugin"
 )
@@ -41,14 +44,38 @@ type Meta struct {
 	Path string
 	// Enabled reports whether or not the plugin should be loaded
 	Enabled bool
+	// Digest is the sha256 digest of the plugin's SIF image as stored in
+	// the blobstore under DirRoot/blobs/sha256/<Digest>. Plugins installed
+	// from the same SIF share the underlying blob.
+	Digest string
+	// Manifest is the manifest read from the plugin's SIF at install time,
+	// carried in Meta's JSON form so Privileges() and Settings() can be
+	// answered without re-opening the SIF.
+	Manifest pluginapi.Manifest
 
-	fimg   *sif.FileImage // Plugin SIF object
-	binary *plugin.Plugin // Plugin binary object
-	cfg    *os.File       // Plugin YAML config file
+	fimg      *sif.FileImage // Plugin SIF object
+	binary    *plugin.Plugin // Plugin binary object, set once Start loads an in-process plugin
+	cfg       *os.File       // Plugin YAML config file
+	transport Transport      // Active Transport, set by Start
 
 	file *os.File // Pointer to Meta file on disk, for Read/Write access
 }
 
+// Descriptor identifies a plugin SIF image to be installed: an opened
+// *sif.FileImage together with the digest of its backing blob in the
+// blobstore. NewFromImage consumes a Descriptor rather than a raw
+// *sif.FileImage so that plugins pulled via the distribution subsystem
+// (see plugin.Install) can be deduplicated by content.
+type Descriptor struct {
+	// FileImage is the opened plugin SIF.
+	FileImage *sif.FileImage
+	// Digest is the sha256 digest of the blob backing FileImage, as
+	// computed by the blobstore when the image was pulled. A SIF opened
+	// from local disk has none. An empty Digest means the image has not been stored in
+	// the blobstore yet, and NewFromImage will compute and store it.
+	Digest string
+}
+
 // LoadFromJSON loads a Meta type from an io.Reader containing JSON. A plugin Meta
 // object created in this form is read-only.
 func LoadFromJSON(r io.Reader) (*Meta, error) {
@@ -72,63 +99,172 @@ func (m *Meta) Config() (*os.File, error) {
 	return os.Open(m.configName())
 }
 
+// Privileges returns the elevated capabilities the plugin's manifest
+// requests of the host. A caller must obtain explicit acceptance of a
+// non-empty Privileges before the plugin it belongs to is enabled.
+func (m *Meta) Privileges() pluginapi.Privileges {
+	return m.Manifest.Privileges
+}
+
+// Start loads the plugin using the Transport selected by its manifest:
+// in-process via Go's plugin.Plugin, or out-of-process over a Unix socket
+// RPC connection discovered under libexecdir/DirRoot/run.
+func (m *Meta) Start(libexecdir string) error {
+	t := m.transportFor(libexecdir)
+	if err := t.Start(); err != nil {
+		return err
+	}
+	m.transport = t
+	return nil
+}
+
+// Stop releases the plugin's Transport. A RuntimeSocket plugin is
+// disconnected from; a RuntimeInProcess plugin, once loaded into the
+// process, cannot be unloaded without restarting singularity.
+func (m *Meta) Stop() error {
+	if m.transport == nil {
+		return nil
+	}
+	return m.transport.Stop()
+}
+
+// HealthCheck reports whether the plugin is still responsive. A failing
+// HealthCheck disables the plugin so that a broken socket plugin can be
+// taken out of rotation without a singularity restart.
+func (m *Meta) HealthCheck() error {
+	if m.transport == nil {
+		return fmt.Errorf("plugin %s has not been started", m.Name)
+	}
+
+	if err := m.transport.HealthCheck(); err != nil {
+		m.Enabled = false
+		return fmt.Errorf("plugin %s failed health check, disabling: %s", m.Name, err)
+	}
+
+	return nil
+}
+
 // NewFromImage returns a new meta object which hasn't yet been installed from
-// a pointer to an on disk SIF. It will:
+// a Descriptor wrapping an on disk SIF. It will:
 //     1. Check that the SIF is a valid plugin
 //     2. Open the Manifest to retrieve name and calculate the path
-//     3. Copy the SIF into the plugin path
-//     4. Extract the binary object into the path
-//     5. Generate a default config file in the path
-//     6. Write the Meta struct onto disk in DirRoot
-func NewFromImage(fimg *sif.FileImage, libexecdir string) (*Meta, error) {
+//     3. Verify the SIF's signature against policy, and obtain acceptance
+//        of any privileges the manifest requests
+//     4. Store the SIF in the blobstore, keyed by its digest
+//     5. Extract the binary object into the path
+//     6. Generate a default config file in the path
+//
+// The Meta struct itself is not written to disk; the returned *Meta exists
+// only in memory.
+//
+// desc.Digest may be empty, in which case the digest is computed while the
+// image is stored in the blobstore. A nil policy is treated as the
+// strictest default: unsigned plugins are rejected.
+func NewFromImage(desc Descriptor, libexecdir string, policy *Policy, opts InstallOptions) (*Meta, error) {
+	fimg := desc.FileImage
 	if !isPluginFile(fimg) {
 		return nil, fmt.Errorf("while opening sif file: not a valid plugin")
 	}
 
 	manifest := getManifest(fimg)
-	abspath, err := filepath.Abs(filepath.Join(libexecdir, pathFromManifest(manifest)))
+
+	if policy == nil {
+		policy = &Policy{}
+	}
+	if err := verifySignature(fimg, policy.Keyring); err != nil {
+		if !policy.AllowUnsigned {
+			return nil, fmt.Errorf("while verifying plugin %s: %s", manifest.Name, err)
+		}
+	}
+
+	if !opts.acceptPrivileges(manifest.Privileges) {
+		return nil, fmt.Errorf("plugin %s requests privileges which were not granted: %+v", manifest.Name, manifest.Privileges)
+	}
+
+	// opts.Alias, when set, renames the plugin before its on-disk path is
+	// derived, so that two aliases of the same upstream plugin land in
+	// distinct directories instead of colliding.
+	name := manifest.Name
+	if opts.Alias != "" {
+		name = opts.Alias
+	}
+
+	abspath, err := filepath.Abs(filepath.Join(libexecdir, pathFromName(name)))
 	if err != nil {
 		return nil, fmt.Errorf("while getting absolute path to plugin installation: %s", err)
 	}
 
 	m := &Meta{
-		Name:    manifest.Name,
-		Path:    abspath,
-		Enabled: true,
+		Name:     name,
+		Path:     abspath,
+		Enabled:  true,
+		Manifest: manifest,
 
 		fimg: fimg,
 	}
 
-	m.installTo(libexecdir)
+	if err := m.installTo(libexecdir, desc); err != nil {
+		return nil, fmt.Errorf("while installing plugin %s: %s", m.Name, err)
+	}
+
 	return m, nil
 }
 
-// installTo installs the plugin represented by m into libexecdir. This should
-// normally only be called in NewFromImage
-func (m *Meta) installTo(libexecdir string) {
+// installTo installs the plugin represented by m into libexecdir, storing
+// its backing SIF in the blobstore addressed by digest rather than copying
+// it directly into m.Path. This should normally only be called in
+// NewFromImage.
+func (m *Meta) installTo(libexecdir string, desc Descriptor) error {
+	if err := os.MkdirAll(m.Path, 0o755); err != nil {
+		return fmt.Errorf("while creating plugin directory %s: %s", m.Path, err)
+	}
+
+	store := blobstore.New(filepath.Join(libexecdir, DirRoot, "blobs"))
+
+	f, err := os.Open(desc.FileImage.Fp.Name())
+	if err != nil {
+		return fmt.Errorf("while opening plugin SIF: %s", err)
+	}
+	defer f.Close()
+
+	blob, err := store.Put(f)
+	if err != nil {
+		return fmt.Errorf("while storing plugin SIF in blobstore: %s", err)
+	}
+
+	if desc.Digest != "" && desc.Digest != blob.Digest {
+		return fmt.Errorf("plugin SIF digest mismatch: expected %s, got %s", desc.Digest, blob.Digest)
+	}
+	m.Digest = blob.Digest
+
+	if err := os.Symlink(store.BlobPath(m.Digest), m.imageName()); err != nil && !os.IsExist(err) {
+		return fmt.Errorf("while linking plugin SIF into %s: %s", m.Path, err)
+	}
 
+	if err := extractBinary(desc.FileImage, m.binaryName()); err != nil {
+		return err
+	}
+
+	return m.generateDefaultConfig()
 }
 
 //
 // Misc helper functions
 //
 
-// pathFromManifest returns a path which will exist inside of DirRoot and
-// is derived from Manifest.Name
-func pathFromManifest(pluginapi.Manifest) string {
-	return ""
+// pathFromName returns a path which will exist inside of DirRoot and is
+// derived from the plugin's installed name. name is the plugin's
+// effective on-disk name: the manifest's own name, or the --alias it was
+// installed under.
+func pathFromName(name string) string {
+	return filepath.Join(DirRoot, metaFileFromName(name))
 }
 
 // metaFileFromName returns the name of the Meta file from the plugin name, which
 // is a unique string generated by hashing n
 func metaFileFromName(n string) string {
-	return ""
-}
-
-// copyFile copies a file from src -> dst
-func copyFile(src, dst string) error {
-	// copycmd := exec.Command("cp", src, dst)
-	return nil
+	sum := sha256.Sum256([]byte(n))
+	return hex.EncodeToString(sum[:])
 }
 
 //
@@ -146,26 +282,3 @@ func (m *Meta) binaryName() string {
 func (m *Meta) configName() string {
 	return filepath.Join(m.Path, NameConfig)
 }
-
-//
-// Helper functions for fimg *sif.FileImage
-//
-
-// isPluginFile checks if the sif.FileImage contains the sections which
-// make up a valid plugin. A plugin sif file should have the following
-// format:
-//
-// DESCR[0]: Sifplugin
-//   - Datatype: sif.DataPartition
-//   - Fstype:   sif.FsRaw
-//   - Parttype: sif.PartData
-// DESCR[1]: Sifmanifest
-//   - Datatype: sif.DataGenericJSON
-func isPluginFile(fimg *sif.FileImage) bool {
-	return false
-}
-
-// getManifest will extract the Manifest data from the input FileImage
-func getManifest(fimg *sif.FileImage) pluginapi.Manifest {
-	return pluginapi.Manifest{}
-}
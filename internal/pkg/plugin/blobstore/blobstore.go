@@ -0,0 +1,126 @@
+// Copyright (c) 2018, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+// Package blobstore implements a content-addressable store for plugin
+// artifacts, keyed by the sha256 digest of their contents. It is used so
+// that multiple installed plugins which share the same underlying SIF image
+// (for example, two aliases pulled from different registries) only store
+// that image once on disk.
+package blobstore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Descriptor identifies a blob stored in the blobstore by its digest and
+// records its size for validation purposes.
+type Descriptor struct {
+	// Digest is the hex-encoded sha256 digest of the blob, without any
+	// algorithm prefix.
+	Digest string
+	// Size is the size of the blob in bytes.
+	Size int64
+}
+
+// Store is a content-addressable store rooted at a directory, typically
+// "<DirRoot>/blobs".
+type Store struct {
+	root string
+}
+
+// New returns a Store rooted at root. The directory is not created until a
+// blob is written to it.
+func New(root string) *Store {
+	return &Store{root: root}
+}
+
+// BlobPath returns the on-disk path of the blob identified by digest,
+// regardless of whether it has been stored yet.
+func (s *Store) BlobPath(digest string) string {
+	return filepath.Join(s.root, "sha256", digest)
+}
+
+// Has reports whether a blob with the given digest already exists in the
+// store.
+func (s *Store) Has(digest string) bool {
+	_, err := os.Stat(s.BlobPath(digest))
+	return err == nil
+}
+
+// Put streams r into the store, returning a Descriptor for the resulting
+// blob. If a blob with the computed digest already exists, the incoming
+// data is discarded and the existing blob is reused, so that plugins
+// sharing a SIF image share storage.
+func (s *Store) Put(r io.Reader) (Descriptor, error) {
+	dir := filepath.Join(s.root, "sha256")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return Descriptor{}, fmt.Errorf("while creating blobstore directory: %s", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".blob-*")
+	if err != nil {
+		return Descriptor{}, fmt.Errorf("while creating temporary blob: %s", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, h), r)
+	if err != nil {
+		return Descriptor{}, fmt.Errorf("while writing blob: %s", err)
+	}
+
+	digest := hex.EncodeToString(h.Sum(nil))
+	desc := Descriptor{Digest: digest, Size: n}
+
+	if s.Has(digest) {
+		// Identical blob already stored; nothing further to do.
+		return desc, nil
+	}
+
+	if err := tmp.Close(); err != nil {
+		return Descriptor{}, fmt.Errorf("while closing temporary blob: %s", err)
+	}
+	if err := os.Rename(tmp.Name(), s.BlobPath(digest)); err != nil {
+		return Descriptor{}, fmt.Errorf("while storing blob %s: %s", digest, err)
+	}
+
+	return desc, nil
+}
+
+// Open opens the blob identified by digest for reading.
+func (s *Store) Open(digest string) (*os.File, error) {
+	f, err := os.Open(s.BlobPath(digest))
+	if err != nil {
+		return nil, fmt.Errorf("while opening blob %s: %s", digest, err)
+	}
+	return f, nil
+}
+
+// Verify checks that the blob identified by digest has contents matching
+// that digest, returning an error if the blob is missing or corrupt.
+func (s *Store) Verify(digest string) error {
+	f, err := s.Open(digest)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return fmt.Errorf("while hashing blob %s: %s", digest, err)
+	}
+
+	if got := hex.EncodeToString(h.Sum(nil)); got != digest {
+		return fmt.Errorf("blob %s failed verification: computed digest %s", digest, got)
+	}
+
+	return nil
+}
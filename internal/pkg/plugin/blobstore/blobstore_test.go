@@ -0,0 +1,113 @@
+// Copyright (c) 2018, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package blobstore
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestStorePutAndOpen(t *testing.T) {
+	root, err := os.MkdirTemp("", "blobstore-test-")
+	if err != nil {
+		t.Fatalf("while creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(root)
+
+	s := New(root)
+
+	const content = "hello plugin"
+	desc, err := s.Put(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("Put() returned unexpected error: %s", err)
+	}
+	if desc.Size != int64(len(content)) {
+		t.Errorf("Put() Size = %d, want %d", desc.Size, len(content))
+	}
+	if !s.Has(desc.Digest) {
+		t.Errorf("Has(%q) = false after Put", desc.Digest)
+	}
+
+	f, err := s.Open(desc.Digest)
+	if err != nil {
+		t.Fatalf("Open() returned unexpected error: %s", err)
+	}
+	defer f.Close()
+
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(f); err != nil {
+		t.Fatalf("while reading blob: %s", err)
+	}
+	if buf.String() != content {
+		t.Errorf("Open() content = %q, want %q", buf.String(), content)
+	}
+}
+
+func TestStorePutDedupes(t *testing.T) {
+	root, err := os.MkdirTemp("", "blobstore-test-")
+	if err != nil {
+		t.Fatalf("while creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(root)
+
+	s := New(root)
+
+	first, err := s.Put(strings.NewReader("same content"))
+	if err != nil {
+		t.Fatalf("first Put() returned unexpected error: %s", err)
+	}
+	second, err := s.Put(strings.NewReader("same content"))
+	if err != nil {
+		t.Fatalf("second Put() returned unexpected error: %s", err)
+	}
+
+	if first.Digest != second.Digest {
+		t.Errorf("identical content produced different digests: %s != %s", first.Digest, second.Digest)
+	}
+}
+
+func TestStoreVerify(t *testing.T) {
+	root, err := os.MkdirTemp("", "blobstore-test-")
+	if err != nil {
+		t.Fatalf("while creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(root)
+
+	s := New(root)
+
+	desc, err := s.Put(strings.NewReader("verified content"))
+	if err != nil {
+		t.Fatalf("Put() returned unexpected error: %s", err)
+	}
+
+	if err := s.Verify(desc.Digest); err != nil {
+		t.Errorf("Verify() returned unexpected error for intact blob: %s", err)
+	}
+
+	if err := os.WriteFile(s.BlobPath(desc.Digest), []byte("corrupted"), 0o644); err != nil {
+		t.Fatalf("while corrupting blob: %s", err)
+	}
+
+	if err := s.Verify(desc.Digest); err == nil {
+		t.Error("Verify() = nil for corrupted blob, want error")
+	}
+}
+
+func TestStoreVerifyMissing(t *testing.T) {
+	root, err := os.MkdirTemp("", "blobstore-test-")
+	if err != nil {
+		t.Fatalf("while creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(root)
+
+	s := New(root)
+
+	if err := s.Verify("does-not-exist"); err == nil {
+		t.Error("Verify() = nil for missing blob, want error")
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2018, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package plugin
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/sylabs/singularity/internal/pkg/util/fs"
+	pluginapi "github.com/sylabs/singularity/pkg/plugin"
+	"gopkg.in/yaml.v2"
+)
+
+// generateDefaultConfig validates m.Manifest.Settings and writes
+// m.configName() with each setting materialized to its default value, or a
+// fresh cryptographically random value for generated-secret settings. This
+// is called from installTo on every install; it never overwrites an
+// existing config.yaml, such as one left by an earlier install under the
+// same name.
+func (m *Meta) generateDefaultConfig() error {
+	if err := m.Manifest.Settings.Validate(); err != nil {
+		return fmt.Errorf("while validating plugin settings schema: %s", err)
+	}
+
+	if fs.IsFile(m.configName()) {
+		return nil
+	}
+
+	defaults, err := m.Manifest.Settings.Defaults()
+	if err != nil {
+		return fmt.Errorf("while generating default plugin config: %s", err)
+	}
+
+	b, err := yaml.Marshal(defaults)
+	if err != nil {
+		return fmt.Errorf("while marshaling default plugin config: %s", err)
+	}
+
+	if err := os.WriteFile(m.configName(), b, 0o644); err != nil {
+		return fmt.Errorf("while writing %s: %s", m.configName(), err)
+	}
+
+	return nil
+}
+
+// Settings returns the typed settings schema declared by the plugin's
+// manifest.
+func (m *Meta) Settings() pluginapi.Settings {
+	return m.Manifest.Settings
+}
+
+// ValidateConfig decodes the YAML config read from r and checks that every
+// key is declared in the plugin's settings schema, and that dropdown/radio
+// values are one of the declared options. It does not write to the
+// plugin's on-disk config.yaml; a caller round-tripping user edits should
+// persist them itself once ValidateConfig returns nil.
+func (m *Meta) ValidateConfig(r io.Reader) error {
+	var cfg map[string]interface{}
+	if err := yaml.NewDecoder(r).Decode(&cfg); err != nil {
+		return fmt.Errorf("while decoding plugin config: %s", err)
+	}
+
+	schema := make(map[string]pluginapi.Setting, len(m.Manifest.Settings))
+	for _, s := range m.Manifest.Settings {
+		schema[s.Key] = s
+	}
+
+	for k, v := range cfg {
+		s, ok := schema[k]
+		if !ok {
+			return fmt.Errorf("unknown plugin setting %q", k)
+		}
+
+		switch s.Type {
+		case pluginapi.SettingBool:
+			if _, ok := v.(bool); !ok {
+				return fmt.Errorf("setting %q: %v is not a bool", k, v)
+			}
+		case pluginapi.SettingDropdown, pluginapi.SettingRadio:
+			if !hasOption(s, fmt.Sprintf("%v", v)) {
+				return fmt.Errorf("setting %q: %v is not one of the declared options", k, v)
+			}
+		}
+	}
+
+	return nil
+}
+
+// hasOption reports whether v is one of s.Options' declared values.
+func hasOption(s pluginapi.Setting, v string) bool {
+	for _, o := range s.Options {
+		if o.Value == v {
+			return true
+		}
+	}
+	return false
+}
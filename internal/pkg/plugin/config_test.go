@@ -0,0 +1,45 @@
+// Copyright (c) 2018, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package plugin
+
+import (
+	"os"
+	"testing"
+
+	pluginapi "github.com/sylabs/singularity/pkg/plugin"
+)
+
+// TestGenerateDefaultConfigRoundTrips writes a default config.yaml for a
+// manifest whose settings include a SettingBool, then checks that
+// ValidateConfig accepts the file it just generated: the values
+// generateDefaultConfig materializes must be of the types ValidateConfig
+// expects back.
+func TestGenerateDefaultConfigRoundTrips(t *testing.T) {
+	m := &Meta{
+		Path: t.TempDir(),
+		Manifest: pluginapi.Manifest{
+			Settings: pluginapi.Settings{
+				{Key: "enabled", Type: pluginapi.SettingBool, Default: "true"},
+				{Key: "label", Type: pluginapi.SettingText, Default: "hello"},
+				{Key: "mode", Type: pluginapi.SettingDropdown, Default: "a", Options: []pluginapi.SettingOption{{Value: "a"}, {Value: "b"}}},
+			},
+		},
+	}
+
+	if err := m.generateDefaultConfig(); err != nil {
+		t.Fatalf("generateDefaultConfig() returned unexpected error: %s", err)
+	}
+
+	f, err := os.Open(m.configName())
+	if err != nil {
+		t.Fatalf("while opening generated config: %s", err)
+	}
+	defer f.Close()
+
+	if err := m.ValidateConfig(f); err != nil {
+		t.Errorf("ValidateConfig() rejected the config generateDefaultConfig just wrote: %s", err)
+	}
+}
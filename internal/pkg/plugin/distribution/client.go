@@ -0,0 +1,377 @@
+// Copyright (c) 2018, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package distribution
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// clientTimeout bounds how long a single registry/library HTTP request
+// waits for a response, so a pull/push can't hang indefinitely against an
+// unresponsive remote.
+const clientTimeout = 60 * time.Second
+
+var httpClient = &http.Client{Timeout: clientTimeout}
+
+// Client pulls and pushes plugin SIF images identified by a Ref.
+type Client interface {
+	// Pull returns a reader over the raw SIF bytes for ref.
+	Pull(ref Ref) (io.ReadCloser, error)
+	// Push uploads the SIF read from r to ref.
+	Push(ref Ref, r io.Reader) error
+}
+
+// NewClient returns the Client implementation appropriate for ref.Scheme.
+func NewClient(ref Ref) (Client, error) {
+	switch ref.Scheme {
+	case SchemeOras:
+		return &orasClient{}, nil
+	case SchemeLibrary:
+		return &libraryClient{}, nil
+	default:
+		return nil, fmt.Errorf("no distribution client for scheme %q", ref.Scheme)
+	}
+}
+
+// ociManifestMediaType is the media type requested and produced for the OCI
+// image manifest wrapping a pulled/pushed plugin SIF blob.
+const ociManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+
+// pluginLayerMediaType identifies a plugin SIF layer within an OCI image
+// manifest.
+const pluginLayerMediaType = "application/vnd.sylabs.plugin.sif.layer.v1"
+
+// ociManifest is the subset of the OCI image manifest schema the plugin
+// distribution client needs: a single config blob (unused, but required by
+// the schema) and one layer per platform asset.
+type ociManifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType"`
+	Config        ociDescriptor     `json:"config"`
+	Layers        []ociDescriptor   `json:"layers"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+// ociDescriptor is an OCI content descriptor: a digest, size and media type
+// identifying a blob, optionally naming it via the
+// "org.opencontainers.image.title" annotation.
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// titleAnnotation is the OCI annotation key used to name a layer, matched
+// against Ref.Asset to select a platform-specific layer out of a
+// multi-platform manifest.
+const titleAnnotation = "org.opencontainers.image.title"
+
+// orasClient pulls/pushes plugin SIF images as OCI artifact blobs from an
+// OCI-compatible registry, using the plain OCI Distribution API v2: fetch
+// the tag's image manifest, then fetch the blob it points to.
+//
+// Ref.Path is split on its first "/" into the registry host and the
+// repository name, e.g. "registry.example.com/myplugin" resolves against
+// https://registry.example.com/v2/myplugin/....
+type orasClient struct{}
+
+// hostAndRepo splits ref.Path into a registry host and repository name.
+func hostAndRepo(ref Ref) (host, repo string, err error) {
+	host, repo, ok := strings.Cut(ref.Path, "/")
+	if !ok || repo == "" {
+		return "", "", fmt.Errorf("oras reference %q must be of the form host/repository", ref.Path)
+	}
+	return host, repo, nil
+}
+
+func (c *orasClient) fetchManifest(host, repo, tag string) (ociManifest, error) {
+	url := fmt.Sprintf("https://%s/v2/%s/manifests/%s", host, repo, tag)
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return ociManifest{}, err
+	}
+	req.Header.Set("Accept", ociManifestMediaType)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return ociManifest{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return ociManifest{}, fmt.Errorf("unexpected status %s fetching manifest", resp.Status)
+	}
+
+	var m ociManifest
+	if err := json.NewDecoder(resp.Body).Decode(&m); err != nil {
+		return ociManifest{}, fmt.Errorf("while decoding OCI manifest: %s", err)
+	}
+
+	return m, nil
+}
+
+// selectLayer returns the manifest layer to pull: the one whose title
+// annotation matches asset, or the sole layer if asset is empty.
+func selectLayer(m ociManifest, asset string) (ociDescriptor, error) {
+	if asset == "" {
+		if len(m.Layers) != 1 {
+			return ociDescriptor{}, fmt.Errorf("manifest has %d layers, an asset name is required to select one", len(m.Layers))
+		}
+		return m.Layers[0], nil
+	}
+
+	for _, l := range m.Layers {
+		if l.Annotations[titleAnnotation] == asset {
+			return l, nil
+		}
+	}
+
+	return ociDescriptor{}, fmt.Errorf("no layer named %q in manifest", asset)
+}
+
+func (c *orasClient) Pull(ref Ref) (io.ReadCloser, error) {
+	host, repo, err := hostAndRepo(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := c.fetchManifest(host, repo, ref.Tag)
+	if err != nil {
+		return nil, fmt.Errorf("while fetching manifest for %s: %s", ref, err)
+	}
+
+	layer, err := selectLayer(m, ref.Asset)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("https://%s/v2/%s/blobs/%s", host, repo, layer.Digest)
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("while fetching blob %s: %s", layer.Digest, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status %s fetching blob %s", resp.Status, layer.Digest)
+	}
+
+	rc, err := verifiedBlobReader(resp.Body, layer.Digest)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	return rc, nil
+}
+
+// verifiedBlobReader wraps rc so that the bytes it yields are hashed as
+// they're read, failing the read once rc is exhausted if the computed
+// sha256 digest doesn't match want: a registry (or a MITM) serving bytes
+// that don't match the manifest's advertised digest must never reach the
+// content-addressable blobstore undetected. want is rejected up front if
+// it doesn't name the sha256 algorithm, since that's the only one the
+// blobstore itself addresses content by.
+func verifiedBlobReader(rc io.ReadCloser, want string) (io.ReadCloser, error) {
+	if !strings.HasPrefix(want, "sha256:") {
+		return nil, fmt.Errorf("unsupported digest algorithm in %q", want)
+	}
+
+	return &digestVerifyingReader{ReadCloser: rc, want: want, h: sha256.New()}, nil
+}
+
+// digestVerifyingReader hashes bytes as they're read through it, turning
+// the terminal io.EOF into an error if the accumulated digest doesn't
+// match want.
+type digestVerifyingReader struct {
+	io.ReadCloser
+	want string
+	h    hash.Hash
+}
+
+func (r *digestVerifyingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		r.h.Write(p[:n])
+	}
+	if err == io.EOF {
+		got := "sha256:" + hex.EncodeToString(r.h.Sum(nil))
+		if got != r.want {
+			return n, fmt.Errorf("blob digest mismatch: manifest advertises %s, downloaded content hashes to %s", r.want, got)
+		}
+	}
+	return n, err
+}
+
+// pushBlob uploads the contents of r to repo as a monolithic blob, returning
+// its OCI descriptor.
+func (c *orasClient) pushBlob(host, repo, mediaType string, r io.Reader) (ociDescriptor, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return ociDescriptor{}, fmt.Errorf("while reading blob: %s", err)
+	}
+	sum := sha256.Sum256(b)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+
+	startURL := fmt.Sprintf("https://%s/v2/%s/blobs/uploads/", host, repo)
+	startResp, err := httpClient.Post(startURL, "", nil)
+	if err != nil {
+		return ociDescriptor{}, fmt.Errorf("while starting blob upload: %s", err)
+	}
+	startResp.Body.Close()
+	if startResp.StatusCode != http.StatusAccepted {
+		return ociDescriptor{}, fmt.Errorf("unexpected status %s starting blob upload", startResp.Status)
+	}
+
+	loc := startResp.Header.Get("Location")
+	if loc == "" {
+		return ociDescriptor{}, fmt.Errorf("registry did not return an upload location")
+	}
+
+	uploadURL := fmt.Sprintf("%s%sdigest=%s", loc, uploadURLSep(loc), digest)
+	req, err := http.NewRequest(http.MethodPut, uploadURL, bytes.NewReader(b))
+	if err != nil {
+		return ociDescriptor{}, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	putResp, err := httpClient.Do(req)
+	if err != nil {
+		return ociDescriptor{}, fmt.Errorf("while completing blob upload: %s", err)
+	}
+	defer putResp.Body.Close()
+	if putResp.StatusCode != http.StatusCreated {
+		return ociDescriptor{}, fmt.Errorf("unexpected status %s completing blob upload", putResp.Status)
+	}
+
+	return ociDescriptor{MediaType: mediaType, Digest: digest, Size: int64(len(b))}, nil
+}
+
+// uploadURLSep returns the separator needed to append a query parameter to
+// loc, which may or may not already carry one.
+func uploadURLSep(loc string) string {
+	if strings.Contains(loc, "?") {
+		return "&"
+	}
+	return "?"
+}
+
+func (c *orasClient) Push(ref Ref, r io.Reader) error {
+	host, repo, err := hostAndRepo(ref)
+	if err != nil {
+		return err
+	}
+
+	layer, err := c.pushBlob(host, repo, pluginLayerMediaType, r)
+	if err != nil {
+		return fmt.Errorf("while pushing plugin blob: %s", err)
+	}
+	if ref.Asset != "" {
+		layer.Annotations = map[string]string{titleAnnotation: ref.Asset}
+	}
+
+	config, err := c.pushBlob(host, repo, "application/vnd.oci.empty.v1+json", bytes.NewReader([]byte("{}")))
+	if err != nil {
+		return fmt.Errorf("while pushing manifest config: %s", err)
+	}
+
+	m := ociManifest{
+		SchemaVersion: 2,
+		MediaType:     ociManifestMediaType,
+		Config:        config,
+		Layers:        []ociDescriptor{layer},
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("while marshaling OCI manifest: %s", err)
+	}
+
+	url := fmt.Sprintf("https://%s/v2/%s/manifests/%s", host, repo, ref.Tag)
+	req, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", ociManifestMediaType)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("while pushing manifest: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("unexpected status %s pushing manifest", resp.Status)
+	}
+
+	return nil
+}
+
+// libraryClient pulls/pushes plugin SIF images from a Sylabs Library
+// instance, using the same container-info/imagefile endpoints as the
+// "library://" container image backend.
+//
+// Ref.Path is the library entity/collection/container path, e.g.
+// "myentity/mycollection/myplugin", resolved against the base URL returned
+// by libraryBaseURL.
+type libraryClient struct{}
+
+// libraryBaseURL returns the base URL of the Sylabs Library instance to
+// pull/push against.
+func libraryBaseURL() string {
+	return "https://library.sylabs.io"
+}
+
+func (c *libraryClient) Pull(ref Ref) (io.ReadCloser, error) {
+	path := ref.Path
+	if ref.Asset != "" {
+		path = path + "/" + ref.Asset
+	}
+
+	url := fmt.Sprintf("%s/v1/imagefile/%s:%s", libraryBaseURL(), path, ref.Tag)
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("while fetching library image: %s", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status %s fetching library image %s", resp.Status, ref)
+	}
+
+	return resp.Body, nil
+}
+
+func (c *libraryClient) Push(ref Ref, r io.Reader) error {
+	url := fmt.Sprintf("%s/v1/imagefile/%s:%s", libraryBaseURL(), ref.Path, ref.Tag)
+
+	req, err := http.NewRequest(http.MethodPut, url, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("while pushing library image: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("unexpected status %s pushing library image %s", resp.Status, ref)
+	}
+
+	return nil
+}
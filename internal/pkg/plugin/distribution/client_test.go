@@ -0,0 +1,49 @@
+// Copyright (c) 2018, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package distribution
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strings"
+	"testing"
+)
+
+func digestOf(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func TestVerifiedBlobReaderMatchingDigest(t *testing.T) {
+	data := "plugin blob contents"
+
+	rc, err := verifiedBlobReader(io.NopCloser(strings.NewReader(data)), digestOf(data))
+	if err != nil {
+		t.Fatalf("verifiedBlobReader() returned unexpected error: %s", err)
+	}
+
+	if _, err := io.ReadAll(rc); err != nil {
+		t.Errorf("reading a blob matching its advertised digest returned unexpected error: %s", err)
+	}
+}
+
+func TestVerifiedBlobReaderMismatchedDigest(t *testing.T) {
+	rc, err := verifiedBlobReader(io.NopCloser(strings.NewReader("actual contents")), digestOf("advertised contents"))
+	if err != nil {
+		t.Fatalf("verifiedBlobReader() returned unexpected error: %s", err)
+	}
+
+	if _, err := io.ReadAll(rc); err == nil {
+		t.Error("reading a blob that doesn't match its advertised digest returned nil error, want mismatch error")
+	}
+}
+
+func TestVerifiedBlobReaderUnsupportedAlgorithm(t *testing.T) {
+	if _, err := verifiedBlobReader(io.NopCloser(strings.NewReader("data")), "sha512:deadbeef"); err == nil {
+		t.Error("verifiedBlobReader() = nil error for an unsupported digest algorithm, want error")
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2018, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+// Package distribution resolves plugin install references such as
+// "oras://registry/plugin:tag" or "library://entity/collection/plugin:tag"
+// and pulls/pushes the corresponding SIF images.
+package distribution
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Scheme identifies which backend a Ref should be resolved against.
+type Scheme string
+
+const (
+	// SchemeOras resolves the reference against an OCI registry, pulling
+	// the plugin SIF as an OCI blob.
+	SchemeOras Scheme = "oras"
+	// SchemeLibrary resolves the reference against a Sylabs Library
+	// instance.
+	SchemeLibrary Scheme = "library"
+	// SchemeFile refers to a SIF already present on the local filesystem.
+	SchemeFile Scheme = "file"
+)
+
+// Ref is a parsed plugin install reference.
+type Ref struct {
+	// Scheme selects which client is used to resolve the reference.
+	Scheme Scheme
+	// Path is the scheme-specific path, e.g. "registry/plugin" or
+	// "entity/collection/plugin".
+	Path string
+	// Tag is the version/tag requested, defaulting to "latest" if absent.
+	Tag string
+	// Asset, if non-empty, names the platform-specific artifact to pull
+	// from within the tagged reference, e.g. "object.linux-amd64.so" as
+	// selected by index.SelectPlatform. It is not part of the string
+	// reference syntax and is only ever set programmatically by a caller
+	// that has already resolved a platform.
+	Asset string
+}
+
+// String returns the canonical string form of the Ref.
+func (r Ref) String() string {
+	if r.Scheme == SchemeFile {
+		return r.Path
+	}
+	return fmt.Sprintf("%s://%s:%s", r.Scheme, r.Path, r.Tag)
+}
+
+// Parse parses a plugin install reference. References without a
+// "scheme://" prefix are treated as local file paths.
+func Parse(s string) (Ref, error) {
+	scheme, rest, ok := strings.Cut(s, "://")
+	if !ok {
+		return Ref{Scheme: SchemeFile, Path: s}, nil
+	}
+
+	switch Scheme(scheme) {
+	case SchemeOras, SchemeLibrary:
+	default:
+		return Ref{}, fmt.Errorf("unsupported plugin reference scheme %q", scheme)
+	}
+
+	if rest == "" {
+		return Ref{}, fmt.Errorf("plugin reference %q is missing a path", s)
+	}
+
+	path, tag, ok := strings.Cut(rest, ":")
+	if !ok {
+		tag = "latest"
+	}
+
+	return Ref{
+		Scheme: Scheme(scheme),
+		Path:   path,
+		Tag:    tag,
+	}, nil
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2018, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package distribution
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		ref     string
+		want    Ref
+		wantErr bool
+	}{
+		{
+			name: "file path",
+			ref:  "/path/to/plugin.sif",
+			want: Ref{Scheme: SchemeFile, Path: "/path/to/plugin.sif"},
+		},
+		{
+			name: "relative file path",
+			ref:  "plugin.sif",
+			want: Ref{Scheme: SchemeFile, Path: "plugin.sif"},
+		},
+		{
+			name: "oras with tag",
+			ref:  "oras://registry.example.com/plugin:v1.2.3",
+			want: Ref{Scheme: SchemeOras, Path: "registry.example.com/plugin", Tag: "v1.2.3"},
+		},
+		{
+			name: "oras without tag defaults to latest",
+			ref:  "oras://registry.example.com/plugin",
+			want: Ref{Scheme: SchemeOras, Path: "registry.example.com/plugin", Tag: "latest"},
+		},
+		{
+			name: "library with tag",
+			ref:  "library://entity/collection/plugin:1.0",
+			want: Ref{Scheme: SchemeLibrary, Path: "entity/collection/plugin", Tag: "1.0"},
+		},
+		{
+			name:    "unsupported scheme",
+			ref:     "http://example.com/plugin",
+			wantErr: true,
+		},
+		{
+			name:    "missing path",
+			ref:     "oras://",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.ref)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Parse(%q) = %+v, want error", tt.ref, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q) returned unexpected error: %s", tt.ref, err)
+			}
+			if got != tt.want {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.ref, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRefString(t *testing.T) {
+	tests := []struct {
+		name string
+		ref  Ref
+		want string
+	}{
+		{
+			name: "file",
+			ref:  Ref{Scheme: SchemeFile, Path: "/path/to/plugin.sif"},
+			want: "/path/to/plugin.sif",
+		},
+		{
+			name: "oras",
+			ref:  Ref{Scheme: SchemeOras, Path: "registry.example.com/plugin", Tag: "v1.2.3"},
+			want: "oras://registry.example.com/plugin:v1.2.3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.ref.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
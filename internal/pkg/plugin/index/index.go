@@ -0,0 +1,235 @@
+// Copyright (c) 2018, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+// Package index implements a client for a remote plugin catalog: a list
+// of named plugins, the versions and platform-specific assets each one
+// publishes, and the index URL(s) those catalogs are fetched from.
+package index
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// cacheFileName is the name of the cached catalog under the index's cache
+// directory.
+const cacheFileName = "index.json"
+
+// fetchTimeout bounds how long fetchOne waits for an index server to
+// respond, so an unresponsive index can't hang an install indefinitely.
+const fetchTimeout = 30 * time.Second
+
+var httpClient = &http.Client{Timeout: fetchTimeout}
+
+// Platform is one platform-specific asset a plugin entry publishes, e.g.
+// linux/amd64.
+type Platform struct {
+	// Selector is an "os/arch" expression, e.g. "linux/amd64", matched
+	// against runtime.GOOS/GOARCH.
+	Selector string `json:"selector"`
+	// Bin is the asset name to download for this platform, e.g.
+	// "object.linux-amd64.so".
+	Bin string `json:"bin"`
+}
+
+// Entry describes one plugin published to the index.
+type Entry struct {
+	// Name is the plugin's name, used to Resolve and Search it.
+	Name string `json:"name"`
+	// Repo is the distribution reference prefix used to pull a resolved
+	// version, e.g. "oras://registry/plugin".
+	Repo string `json:"repo"`
+	// Versions lists the semver versions published for this plugin.
+	Versions []string `json:"versions"`
+	// Platforms lists the platform-specific assets published for each
+	// version.
+	Platforms []Platform `json:"platforms"`
+}
+
+// Catalog is the full list of plugins published to one or more indexes.
+type Catalog struct {
+	Entries []Entry `json:"entries"`
+}
+
+// Index is a client for one or more remote plugin catalogs, caching the
+// merged result under cacheDir.
+type Index struct {
+	urls     []string
+	cacheDir string
+}
+
+// New returns an Index which fetches from urls and caches under cacheDir,
+// typically a "plugin" subdirectory of the user's config dir.
+func New(urls []string, cacheDir string) *Index {
+	return &Index{urls: urls, cacheDir: cacheDir}
+}
+
+// Load returns the cached Catalog if present, fetching and caching it
+// otherwise.
+func (i *Index) Load() (Catalog, error) {
+	path := filepath.Join(i.cacheDir, cacheFileName)
+
+	f, err := os.Open(path)
+	if err == nil {
+		defer f.Close()
+		var c Catalog
+		if err := json.NewDecoder(f).Decode(&c); err != nil {
+			return Catalog{}, fmt.Errorf("while decoding cached plugin index: %s", err)
+		}
+		return c, nil
+	}
+
+	return i.Fetch()
+}
+
+// Fetch downloads the catalog from each configured index URL, merges the
+// results, caches the merged catalog under cacheDir, and returns it.
+func (i *Index) Fetch() (Catalog, error) {
+	var merged Catalog
+
+	for _, url := range i.urls {
+		c, err := fetchOne(url)
+		if err != nil {
+			return Catalog{}, fmt.Errorf("while fetching plugin index %s: %s", url, err)
+		}
+		merged.Entries = append(merged.Entries, c.Entries...)
+	}
+
+	if err := i.store(merged); err != nil {
+		return Catalog{}, err
+	}
+
+	return merged, nil
+}
+
+func fetchOne(url string) (Catalog, error) {
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return Catalog{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Catalog{}, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return decodeCatalog(resp.Body)
+}
+
+func decodeCatalog(r io.Reader) (Catalog, error) {
+	var c Catalog
+	if err := json.NewDecoder(r).Decode(&c); err != nil {
+		return Catalog{}, fmt.Errorf("while decoding plugin index: %s", err)
+	}
+	return c, nil
+}
+
+func (i *Index) store(c Catalog) error {
+	if err := os.MkdirAll(i.cacheDir, 0o755); err != nil {
+		return fmt.Errorf("while creating plugin index cache dir %s: %s", i.cacheDir, err)
+	}
+
+	b, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("while marshaling plugin index cache: %s", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(i.cacheDir, cacheFileName), b, 0o644); err != nil {
+		return fmt.Errorf("while writing plugin index cache: %s", err)
+	}
+
+	return nil
+}
+
+// Search returns every Entry in c whose Name or Repo contains query,
+// case-insensitively.
+func (c Catalog) Search(query string) []Entry {
+	query = strings.ToLower(query)
+
+	var matches []Entry
+	for _, e := range c.Entries {
+		if strings.Contains(strings.ToLower(e.Name), query) || strings.Contains(strings.ToLower(e.Repo), query) {
+			matches = append(matches, e)
+		}
+	}
+
+	return matches
+}
+
+// Lookup returns the Entry named name, if present. Callers that need to
+// pick a platform-specific asset before installing should pass the result
+// to SelectPlatform.
+func (c Catalog) Lookup(name string) (Entry, bool) {
+	return c.find(name)
+}
+
+// find returns the Entry named name, if present.
+func (c Catalog) find(name string) (Entry, bool) {
+	for _, e := range c.Entries {
+		if e.Name == name {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Resolve looks up the plugin named name and returns the distribution
+// reference and version of the highest of its published Versions
+// satisfying constraint. An empty constraint matches any version, but
+// picks a pre-release only when no stable version is published.
+func (c Catalog) Resolve(name, constraint string) (ref string, version string, err error) {
+	entry, ok := c.find(name)
+	if !ok {
+		return "", "", fmt.Errorf("no plugin named %q in index", name)
+	}
+
+	var cstr *semver.Constraints
+	if constraint != "" {
+		cstr, err = semver.NewConstraint(constraint)
+		if err != nil {
+			return "", "", fmt.Errorf("while parsing version constraint %q: %s", constraint, err)
+		}
+	}
+
+	var best, bestPre *semver.Version
+	for _, v := range entry.Versions {
+		sv, err := semver.NewVersion(v)
+		if err != nil {
+			continue
+		}
+		// With no explicit constraint, a pre-release version is only
+		// eligible if it's the sole way to satisfy the request: an
+		// unconstrained Resolve should prefer the highest stable release.
+		if cstr == nil && sv.Prerelease() != "" {
+			if bestPre == nil || sv.GreaterThan(bestPre) {
+				bestPre = sv
+			}
+			continue
+		}
+		if cstr != nil && !cstr.Check(sv) {
+			continue
+		}
+		if best == nil || sv.GreaterThan(best) {
+			best = sv
+		}
+	}
+
+	if best == nil {
+		best = bestPre
+	}
+	if best == nil {
+		return "", "", fmt.Errorf("no version of plugin %q satisfies %q", name, constraint)
+	}
+
+	return fmt.Sprintf("%s:%s", entry.Repo, best.Original()), best.Original(), nil
+}
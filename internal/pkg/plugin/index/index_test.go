@@ -0,0 +1,109 @@
+// Copyright (c) 2018, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package index
+
+import "testing"
+
+func testCatalog() Catalog {
+	return Catalog{
+		Entries: []Entry{
+			{
+				Name:     "myplugin",
+				Repo:     "oras://registry.example.com/myplugin",
+				Versions: []string{"1.0.0", "1.2.0", "2.0.0-rc1"},
+			},
+			{
+				Name: "otherplugin",
+				Repo: "library://entity/collection/otherplugin",
+			},
+		},
+	}
+}
+
+func TestCatalogResolve(t *testing.T) {
+	c := testCatalog()
+
+	tests := []struct {
+		name       string
+		plugin     string
+		constraint string
+		wantRef    string
+		wantVer    string
+		wantErr    bool
+	}{
+		{
+			name:    "no constraint picks highest stable version",
+			plugin:  "myplugin",
+			wantRef: "oras://registry.example.com/myplugin:1.2.0",
+			wantVer: "1.2.0",
+		},
+		{
+			name:       "constraint narrows selection",
+			plugin:     "myplugin",
+			constraint: "<1.2.0",
+			wantRef:    "oras://registry.example.com/myplugin:1.0.0",
+			wantVer:    "1.0.0",
+		},
+		{
+			name:       "unsatisfiable constraint",
+			plugin:     "myplugin",
+			constraint: ">3.0.0",
+			wantErr:    true,
+		},
+		{
+			name:    "unknown plugin",
+			plugin:  "doesnotexist",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref, ver, err := c.Resolve(tt.plugin, tt.constraint)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Resolve(%q, %q) = (%q, %q), want error", tt.plugin, tt.constraint, ref, ver)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve(%q, %q) returned unexpected error: %s", tt.plugin, tt.constraint, err)
+			}
+			if ref != tt.wantRef || ver != tt.wantVer {
+				t.Errorf("Resolve(%q, %q) = (%q, %q), want (%q, %q)", tt.plugin, tt.constraint, ref, ver, tt.wantRef, tt.wantVer)
+			}
+		})
+	}
+}
+
+func TestCatalogSearch(t *testing.T) {
+	c := testCatalog()
+
+	matches := c.Search("plugin")
+	if len(matches) != 2 {
+		t.Fatalf("Search(\"plugin\") returned %d entries, want 2", len(matches))
+	}
+
+	matches = c.Search("myplugin")
+	if len(matches) != 1 || matches[0].Name != "myplugin" {
+		t.Errorf("Search(\"myplugin\") = %+v, want a single entry named myplugin", matches)
+	}
+
+	if matches := c.Search("doesnotexist"); len(matches) != 0 {
+		t.Errorf("Search(\"doesnotexist\") = %+v, want no matches", matches)
+	}
+}
+
+func TestCatalogLookup(t *testing.T) {
+	c := testCatalog()
+
+	if _, ok := c.Lookup("myplugin"); !ok {
+		t.Error("Lookup(\"myplugin\") = false, want true")
+	}
+	if _, ok := c.Lookup("doesnotexist"); ok {
+		t.Error("Lookup(\"doesnotexist\") = true, want false")
+	}
+}
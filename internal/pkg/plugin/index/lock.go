@@ -0,0 +1,56 @@
+// Copyright (c) 2018, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package index
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Lock records a plugin install resolved against an Index, so that the
+// same digest can be reproduced on another machine without re-resolving
+// the version constraint against a (possibly since-updated) catalog.
+type Lock struct {
+	// Name is the plugin's name, as passed to Resolve.
+	Name string `json:"name"`
+	// Ref is the distribution reference the plugin was pulled from.
+	Ref string `json:"ref"`
+	// Version is the resolved version satisfying the install constraint.
+	Version string `json:"version"`
+	// Digest is the sha256 digest of the pulled plugin SIF, as stored in
+	// the blobstore.
+	Digest string `json:"digest"`
+}
+
+// WriteLock writes l as JSON to path, creating or truncating it.
+func WriteLock(path string, l Lock) error {
+	b, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return fmt.Errorf("while marshaling plugin lockfile: %s", err)
+	}
+
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		return fmt.Errorf("while writing plugin lockfile %s: %s", path, err)
+	}
+
+	return nil
+}
+
+// ReadLock reads a Lock previously written by WriteLock.
+func ReadLock(path string) (Lock, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Lock{}, fmt.Errorf("while reading plugin lockfile %s: %s", path, err)
+	}
+
+	var l Lock
+	if err := json.Unmarshal(b, &l); err != nil {
+		return Lock{}, fmt.Errorf("while decoding plugin lockfile %s: %s", path, err)
+	}
+
+	return l, nil
+}
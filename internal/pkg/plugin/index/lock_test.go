@@ -0,0 +1,41 @@
+// Copyright (c) 2018, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package index
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteLockReadLockRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plugin-lock.json")
+
+	want := Lock{
+		Name:    "myplugin",
+		Ref:     "oras://registry.example.com/myplugin:1.2.0",
+		Version: "1.2.0",
+		Digest:  "deadbeef",
+	}
+
+	if err := WriteLock(path, want); err != nil {
+		t.Fatalf("WriteLock() returned unexpected error: %s", err)
+	}
+
+	got, err := ReadLock(path)
+	if err != nil {
+		t.Fatalf("ReadLock() returned unexpected error: %s", err)
+	}
+
+	if got != want {
+		t.Errorf("ReadLock() = %+v, want %+v", got, want)
+	}
+}
+
+func TestReadLockMissing(t *testing.T) {
+	if _, err := ReadLock(filepath.Join(t.TempDir(), "does-not-exist.json")); err == nil {
+		t.Error("ReadLock() = nil error for a missing lockfile, want error")
+	}
+}
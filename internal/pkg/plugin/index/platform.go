@@ -0,0 +1,48 @@
+// Copyright (c) 2018, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package index
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// Asset is the platform-specific artifact selected for the running host.
+type Asset struct {
+	// Bin is the asset name to download, e.g. "object.linux-amd64.so".
+	Bin string
+}
+
+// SelectPlatform returns the Platform asset in entry matching the running
+// host's runtime.GOOS/GOARCH, mirroring Trivy's plugin platform selection:
+// each Platform declares a "os/arch" selector, e.g.
+// {selector: "linux/amd64", bin: "object.linux-amd64.so"}. "*" may be used
+// for either component as a wildcard.
+func SelectPlatform(entry Entry) (Asset, error) {
+	want := fmt.Sprintf("%s/%s", runtime.GOOS, runtime.GOARCH)
+
+	for _, p := range entry.Platforms {
+		if matchesSelector(p.Selector, want) {
+			return Asset{Bin: p.Bin}, nil
+		}
+	}
+
+	return Asset{}, fmt.Errorf("plugin %q publishes no asset for platform %s", entry.Name, want)
+}
+
+// matchesSelector reports whether selector ("os/arch", components may be
+// "*") matches want ("os/arch").
+func matchesSelector(selector, want string) bool {
+	sParts := strings.SplitN(selector, "/", 2)
+	wParts := strings.SplitN(want, "/", 2)
+	if len(sParts) != 2 || len(wParts) != 2 {
+		return false
+	}
+
+	return (sParts[0] == "*" || sParts[0] == wParts[0]) &&
+		(sParts[1] == "*" || sParts[1] == wParts[1])
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2018, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package index
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+)
+
+func TestMatchesSelector(t *testing.T) {
+	want := fmt.Sprintf("%s/%s", runtime.GOOS, runtime.GOARCH)
+
+	tests := []struct {
+		name     string
+		selector string
+		want     string
+		matches  bool
+	}{
+		{name: "exact match", selector: want, want: want, matches: true},
+		{name: "os wildcard", selector: "*/" + runtime.GOARCH, want: want, matches: true},
+		{name: "arch wildcard", selector: runtime.GOOS + "/*", want: want, matches: true},
+		{name: "both wildcard", selector: "*/*", want: want, matches: true},
+		{name: "os mismatch", selector: "nonexistent-os/" + runtime.GOARCH, want: want, matches: false},
+		{name: "arch mismatch", selector: runtime.GOOS + "/nonexistent-arch", want: want, matches: false},
+		{name: "malformed selector", selector: runtime.GOOS, want: want, matches: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchesSelector(tt.selector, tt.want); got != tt.matches {
+				t.Errorf("matchesSelector(%q, %q) = %v, want %v", tt.selector, tt.want, got, tt.matches)
+			}
+		})
+	}
+}
+
+func TestSelectPlatform(t *testing.T) {
+	want := fmt.Sprintf("%s/%s", runtime.GOOS, runtime.GOARCH)
+
+	entry := Entry{
+		Name: "myplugin",
+		Platforms: []Platform{
+			{Selector: "nonexistent-os/nonexistent-arch", Bin: "object.wrong.so"},
+			{Selector: want, Bin: "object.right.so"},
+		},
+	}
+
+	asset, err := SelectPlatform(entry)
+	if err != nil {
+		t.Fatalf("SelectPlatform() returned unexpected error: %s", err)
+	}
+	if asset.Bin != "object.right.so" {
+		t.Errorf("SelectPlatform() = %+v, want Bin %q", asset, "object.right.so")
+	}
+}
+
+func TestSelectPlatformNoMatch(t *testing.T) {
+	entry := Entry{
+		Name: "myplugin",
+		Platforms: []Platform{
+			{Selector: "nonexistent-os/nonexistent-arch", Bin: "object.wrong.so"},
+		},
+	}
+
+	if _, err := SelectPlatform(entry); err == nil {
+		t.Error("SelectPlatform() = nil error for a plugin with no matching platform, want error")
+	}
+}
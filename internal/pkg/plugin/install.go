@@ -0,0 +1,287 @@
+// Copyright (c) 2018, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sylabs/sif/pkg/sif"
+	"github.com/sylabs/singularity/internal/pkg/plugin/blobstore"
+	"github.com/sylabs/singularity/internal/pkg/plugin/distribution"
+	"github.com/sylabs/singularity/internal/pkg/plugin/index"
+	pluginapi "github.com/sylabs/singularity/pkg/plugin"
+)
+
+// InstallOptions customizes how Install resolves and installs a plugin
+// reference.
+type InstallOptions struct {
+	// Alias, if non-empty, overrides the plugin's manifest name on disk so
+	// that an install from a remote registry doesn't collide with an
+	// existing plugin of the same upstream name.
+	Alias string
+	// AllowUnsigned permits installing a plugin whose SIF carries no
+	// verifiable signature, overriding a stricter Policy for this one
+	// install.
+	AllowUnsigned bool
+	// GrantAllPrivileges accepts every privilege the plugin's manifest
+	// requests without prompting, equivalent to the --grant-all-privileges
+	// CLI flag.
+	GrantAllPrivileges bool
+	// Accept is called with the plugin's requested privileges when the
+	// manifest requests at least one and GrantAllPrivileges is false. It
+	// should return true to proceed with installation. A nil Accept with
+	// GrantAllPrivileges false causes Install to reject any plugin that
+	// requests privileges.
+	Accept func(pluginapi.Privileges) bool
+}
+
+// acceptPrivileges reports whether the privileges p, requested by a
+// plugin's manifest, should be granted under opts.
+func (opts InstallOptions) acceptPrivileges(p pluginapi.Privileges) bool {
+	if p.None() {
+		return true
+	}
+	if opts.GrantAllPrivileges {
+		return true
+	}
+	if opts.Accept != nil {
+		return opts.Accept(p)
+	}
+	return false
+}
+
+// Pull parses ref and fetches the plugin SIF it points to as PullRef
+// does, returning a Descriptor ready to be passed to NewFromImage.
+func Pull(ref string, libexecdir string) (Descriptor, error) {
+	r, err := distribution.Parse(ref)
+	if err != nil {
+		return Descriptor{}, fmt.Errorf("while parsing plugin reference %q: %s", ref, err)
+	}
+
+	return PullRef(r, libexecdir)
+}
+
+// PullRef downloads the plugin SIF identified by r into the blobstore
+// rooted at libexecdir/DirRoot/blobs, returning a Descriptor ready to be
+// passed to NewFromImage. Unlike Pull, r.Asset may be set to request a
+// specific platform artifact from within a multi-platform reference, as
+// resolved by index.SelectPlatform. A local file reference is opened in
+// place rather than stored, and its Descriptor has an empty Digest.
+func PullRef(r distribution.Ref, libexecdir string) (Descriptor, error) {
+	if r.Scheme == distribution.SchemeFile {
+		fimg, err := sif.LoadContainer(r.Path, true)
+		if err != nil {
+			return Descriptor{}, fmt.Errorf("while loading plugin SIF %s: %s", r.Path, err)
+		}
+		return Descriptor{FileImage: &fimg}, nil
+	}
+
+	client, err := distribution.NewClient(r)
+	if err != nil {
+		return Descriptor{}, err
+	}
+
+	rc, err := client.Pull(r)
+	if err != nil {
+		return Descriptor{}, fmt.Errorf("while pulling plugin %s: %s", r, err)
+	}
+	defer rc.Close()
+
+	store := blobstore.New(libexecdir + "/" + DirRoot + "/blobs")
+	blob, err := store.Put(rc)
+	if err != nil {
+		return Descriptor{}, fmt.Errorf("while storing pulled plugin %s: %s", r, err)
+	}
+
+	f, err := store.Open(blob.Digest)
+	if err != nil {
+		return Descriptor{}, err
+	}
+
+	fimg, err := sif.LoadContainerFp(f, true)
+	if err != nil {
+		return Descriptor{}, fmt.Errorf("while opening pulled plugin SIF: %s", err)
+	}
+
+	return Descriptor{FileImage: &fimg, Digest: blob.Digest}, nil
+}
+
+// Push uploads the plugin SIF at sifPath to ref.
+func Push(sifPath string, ref string) error {
+	r, err := distribution.Parse(ref)
+	if err != nil {
+		return fmt.Errorf("while parsing plugin reference %q: %s", ref, err)
+	}
+	if r.Scheme == distribution.SchemeFile {
+		return fmt.Errorf("cannot push to a local file reference %q", ref)
+	}
+
+	client, err := distribution.NewClient(r)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(sifPath)
+	if err != nil {
+		return fmt.Errorf("while opening %s: %s", sifPath, err)
+	}
+	defer f.Close()
+
+	if err := client.Push(r, f); err != nil {
+		return fmt.Errorf("while pushing plugin to %s: %s", r, err)
+	}
+
+	return nil
+}
+
+// Install pulls ref, if necessary, and installs the resulting plugin SIF
+// into libexecdir, optionally renaming it per opts.Alias to avoid name
+// collisions with plugins of the same name from a different upstream.
+// configDir is the root singularity config directory, from which the
+// plugin Policy (signature/unsigned handling) is loaded.
+func Install(ref string, libexecdir string, configDir string, opts InstallOptions) (*Meta, error) {
+	desc, err := Pull(ref, libexecdir)
+	if err != nil {
+		return nil, err
+	}
+
+	return installDescriptor(desc, libexecdir, configDir, opts)
+}
+
+// installDescriptor applies Policy and InstallOptions to an already-pulled
+// Descriptor, shared by Install and InstallFromIndex. opts.Alias, if set,
+// is threaded into NewFromImage so that the renamed plugin's on-disk path
+// is derived from the alias rather than the upstream manifest name.
+func installDescriptor(desc Descriptor, libexecdir string, configDir string, opts InstallOptions) (*Meta, error) {
+	policy, err := LoadPolicy(configDir)
+	if err != nil {
+		return nil, err
+	}
+	if opts.AllowUnsigned {
+		policy.AllowUnsigned = true
+	}
+
+	return NewFromImage(desc, libexecdir, policy, opts)
+}
+
+// lockFileName is the name of the reproducible-install lockfile written
+// next to a plugin installed via InstallFromIndex.
+const lockFileName = "plugin-lock.json"
+
+// InstallFromIndex resolves name against the plugin catalog fetched from
+// indexURLs, honoring the semver constraint, selects the asset matching
+// the running platform, and installs it exactly as Install would. It
+// additionally writes a lockfile recording the resolved digest, so that
+// the same install can be reproduced on another machine without
+// re-resolving the constraint against a catalog that may have since
+// changed.
+func InstallFromIndex(name, constraint string, libexecdir, configDir string, indexURLs []string, opts InstallOptions) (*Meta, error) {
+	idx := index.New(indexURLs, filepath.Join(configDir, "plugin", "index"))
+
+	catalog, err := idx.Load()
+	if err != nil {
+		return nil, fmt.Errorf("while loading plugin index: %s", err)
+	}
+
+	ref, version, err := catalog.Resolve(name, constraint)
+	if err != nil {
+		// The cached catalog may be stale: refetch once before giving up,
+		// so a version published after the last Load doesn't stay
+		// invisible until something else happens to refresh the cache.
+		var ferr error
+		catalog, ferr = idx.Fetch()
+		if ferr != nil {
+			return nil, fmt.Errorf("while resolving plugin %q: %s", name, err)
+		}
+		ref, version, err = catalog.Resolve(name, constraint)
+		if err != nil {
+			return nil, fmt.Errorf("while resolving plugin %q: %s", name, err)
+		}
+	}
+
+	entry, ok := catalog.Lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("plugin %q disappeared from the index between resolve and lookup", name)
+	}
+
+	asset, err := index.SelectPlatform(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := distribution.Parse(ref)
+	if err != nil {
+		return nil, fmt.Errorf("while parsing resolved plugin reference %q: %s", ref, err)
+	}
+	r.Asset = asset.Bin
+
+	desc, err := PullRef(r, libexecdir)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := installDescriptor(desc, libexecdir, configDir, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	lock := index.Lock{
+		Name:    name,
+		Ref:     ref,
+		Version: version,
+		Digest:  m.Digest,
+	}
+	if err := index.WriteLock(filepath.Join(m.Path, lockFileName), lock); err != nil {
+		return nil, fmt.Errorf("while writing plugin lockfile: %s", err)
+	}
+
+	return m, nil
+}
+
+// InstallFromLock reproduces a plugin install recorded by InstallFromIndex:
+// it reads the lockfile at lockPath, pulls lock.Ref exactly as originally
+// resolved, and installs it only if the pulled SIF's digest matches
+// lock.Digest. This lets the same install be reproduced on another machine
+// without re-resolving the version constraint against a catalog that may
+// have since changed, and fails closed if the upstream reference has
+// started serving different content than what was locked.
+func InstallFromLock(lockPath, libexecdir, configDir string, opts InstallOptions) (*Meta, error) {
+	lock, err := index.ReadLock(lockPath)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := distribution.Parse(lock.Ref)
+	if err != nil {
+		return nil, fmt.Errorf("while parsing locked plugin reference %q: %s", lock.Ref, err)
+	}
+
+	desc, err := PullRef(r, libexecdir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check the digest before the plugin is installed. A local file
+	// reference carries no digest yet, so lock.Digest is handed to
+	// installTo, which rejects a mismatch before linking or extracting.
+	if desc.Digest != "" && desc.Digest != lock.Digest {
+		return nil, fmt.Errorf("plugin %q digest mismatch: lockfile %s expects %s, pulled %s", lock.Name, lockPath, lock.Digest, desc.Digest)
+	}
+	desc.Digest = lock.Digest
+
+	m, err := installDescriptor(desc, libexecdir, configDir, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.Digest != lock.Digest {
+		return nil, fmt.Errorf("plugin %q digest mismatch: lockfile %s expects %s, pulled %s", lock.Name, lockPath, lock.Digest, m.Digest)
+	}
+
+	return m, nil
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2018, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// policyFileName is the name of the Policy file inside the root config dir.
+const policyFileName = "plugin-policy.json"
+
+// Policy controls how the plugin installer treats signature verification.
+// It is read once from the root config dir and may be overridden per
+// invocation by InstallOptions.AllowUnsigned.
+type Policy struct {
+	// AllowUnsigned permits installing plugins whose SIF carries no
+	// verifiable signature. Defaults to false: unsigned plugins are
+	// rejected unless this is set, either here or via
+	// InstallOptions.AllowUnsigned.
+	AllowUnsigned bool `json:"allowUnsigned"`
+	// Keyring is the path to the armored PGP keyring used to verify
+	// plugin signatures. If empty, the default singularity keyring is
+	// used.
+	Keyring string `json:"keyring,omitempty"`
+}
+
+// LoadPolicy reads the plugin Policy from configDir, the root singularity
+// config directory. A missing file is not an error: it yields the
+// zero-value Policy, which rejects unsigned plugins and uses the default
+// keyring.
+func LoadPolicy(configDir string) (*Policy, error) {
+	p := &Policy{}
+
+	f, err := os.Open(filepath.Join(configDir, policyFileName))
+	if os.IsNotExist(err) {
+		return p, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("while opening plugin policy: %s", err)
+	}
+	defer f.Close()
+
+	if err := json.NewDecoder(f).Decode(p); err != nil {
+		return nil, fmt.Errorf("while decoding plugin policy: %s", err)
+	}
+
+	return p, nil
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2018, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/sylabs/sif/pkg/sif"
+	pluginapi "github.com/sylabs/singularity/pkg/plugin"
+)
+
+// pluginPartitionDescriptor returns the Sifplugin data partition descriptor
+// in fimg: a DataPartition descriptor with Fstype FsRaw and Parttype
+// PartData, holding the plugin's extracted object.so.
+func pluginPartitionDescriptor(fimg *sif.FileImage) (*sif.Descriptor, error) {
+	for i := range fimg.DescrArr {
+		d := &fimg.DescrArr[i]
+		if !d.Used || d.Datatype != sif.DataPartition {
+			continue
+		}
+
+		fstype, parttype, _, err := d.GetPartitionMetadata()
+		if err != nil {
+			continue
+		}
+		if fstype == sif.FsRaw && parttype == sif.PartData {
+			return d, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no Sifplugin data partition descriptor found")
+}
+
+// manifestDescriptor returns the Sifmanifest JSON descriptor in fimg.
+func manifestDescriptor(fimg *sif.FileImage) (*sif.Descriptor, error) {
+	for i := range fimg.DescrArr {
+		d := &fimg.DescrArr[i]
+		if d.Used && d.Datatype == sif.DataGenericJSON {
+			return d, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no Sifmanifest JSON descriptor found")
+}
+
+// descriptorByID returns the descriptor in fimg whose ID is id.
+func descriptorByID(fimg *sif.FileImage, id uint32) (*sif.Descriptor, error) {
+	for i := range fimg.DescrArr {
+		d := &fimg.DescrArr[i]
+		if d.Used && d.ID == id {
+			return d, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no descriptor with ID %d", id)
+}
+
+// isPluginFile checks if the sif.FileImage contains the sections which
+// make up a valid plugin. A plugin sif file should have the following
+// format:
+//
+// DESCR[0]: Sifplugin
+//   - Datatype: sif.DataPartition
+//   - Fstype:   sif.FsRaw
+//   - Parttype: sif.PartData
+// DESCR[1]: Sifmanifest
+//   - Datatype: sif.DataGenericJSON
+func isPluginFile(fimg *sif.FileImage) bool {
+	if _, err := pluginPartitionDescriptor(fimg); err != nil {
+		return false
+	}
+	if _, err := manifestDescriptor(fimg); err != nil {
+		return false
+	}
+	return true
+}
+
+// getManifest extracts and decodes the Manifest stored in the Sifmanifest
+// descriptor of fimg. It returns the zero Manifest if fimg carries no
+// manifest descriptor or the descriptor's contents cannot be decoded;
+// callers that require a manifest should have already validated fimg with
+// isPluginFile.
+func getManifest(fimg *sif.FileImage) pluginapi.Manifest {
+	d, err := manifestDescriptor(fimg)
+	if err != nil {
+		return pluginapi.Manifest{}
+	}
+
+	data, err := d.GetData(fimg)
+	if err != nil {
+		return pluginapi.Manifest{}
+	}
+
+	var m pluginapi.Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return pluginapi.Manifest{}
+	}
+
+	return m
+}
+
+// extractBinary writes the plugin's object.so, read out of fimg's
+// Sifplugin data partition, to dst.
+func extractBinary(fimg *sif.FileImage, dst string) error {
+	d, err := pluginPartitionDescriptor(fimg)
+	if err != nil {
+		return fmt.Errorf("while locating plugin object partition: %s", err)
+	}
+
+	data, err := d.GetData(fimg)
+	if err != nil {
+		return fmt.Errorf("while reading plugin object partition: %s", err)
+	}
+
+	if err := os.WriteFile(dst, data, 0o755); err != nil {
+		return fmt.Errorf("while writing %s: %s", dst, err)
+	}
+
+	return nil
+}
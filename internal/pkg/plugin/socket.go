@@ -0,0 +1,78 @@
+// Copyright (c) 2018, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// discoverSocket locates the Unix socket for the plugin named name under
+// runDir, following the discovery conventions from the Docker plugin
+// ecosystem:
+//  1. runDir/<name>.sock
+//  2. runDir/<name>/<name>.sock
+//  3. a JSON spec file runDir/<name>.json or runDir/<name>.spec naming an
+//     Addr to dial
+//
+// Candidate 2 is only matched when the containing directory is named
+// exactly <name>; discoverSocket deliberately does not walk runDir
+// recursively looking for any file named <name>.sock, which is the bug
+// that once let Docker's plugin discovery pick up an unrelated socket
+// nested several directories deep.
+func discoverSocket(runDir, name string) (string, error) {
+	direct := filepath.Join(runDir, name+".sock")
+	if isSocket(direct) {
+		return direct, nil
+	}
+
+	nested := filepath.Join(runDir, name, name+".sock")
+	if isSocket(nested) {
+		return nested, nil
+	}
+
+	for _, ext := range []string{".json", ".spec"} {
+		if addr, err := readSpecFile(filepath.Join(runDir, name+ext)); err == nil {
+			return addr, nil
+		}
+	}
+
+	return "", fmt.Errorf("no socket found for plugin %q under %s", name, runDir)
+}
+
+// isSocket reports whether path exists and is a Unix domain socket.
+func isSocket(path string) bool {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeSocket != 0
+}
+
+// pluginSpec is the JSON body of a Docker-style plugin spec file, naming
+// the address of an already-running plugin's socket.
+type pluginSpec struct {
+	Addr string `json:"Addr"`
+}
+
+func readSpecFile(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	var spec pluginSpec
+	if err := json.Unmarshal(b, &spec); err != nil {
+		return "", fmt.Errorf("while parsing plugin spec %s: %s", path, err)
+	}
+	if spec.Addr == "" {
+		return "", fmt.Errorf("plugin spec %s has no Addr", path)
+	}
+
+	return spec.Addr, nil
+}
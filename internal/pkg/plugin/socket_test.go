@@ -0,0 +1,135 @@
+// Copyright (c) 2018, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package plugin
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiscoverSocketDirect(t *testing.T) {
+	runDir := t.TempDir()
+
+	l, err := net.Listen("unix", filepath.Join(runDir, "myplugin.sock"))
+	if err != nil {
+		t.Fatalf("while creating test socket: %s", err)
+	}
+	defer l.Close()
+
+	got, err := discoverSocket(runDir, "myplugin")
+	if err != nil {
+		t.Fatalf("discoverSocket() returned unexpected error: %s", err)
+	}
+	if want := filepath.Join(runDir, "myplugin.sock"); got != want {
+		t.Errorf("discoverSocket() = %q, want %q", got, want)
+	}
+}
+
+func TestDiscoverSocketNested(t *testing.T) {
+	runDir := t.TempDir()
+
+	nestedDir := filepath.Join(runDir, "myplugin")
+	if err := os.MkdirAll(nestedDir, 0o755); err != nil {
+		t.Fatalf("while creating nested dir: %s", err)
+	}
+
+	l, err := net.Listen("unix", filepath.Join(nestedDir, "myplugin.sock"))
+	if err != nil {
+		t.Fatalf("while creating test socket: %s", err)
+	}
+	defer l.Close()
+
+	got, err := discoverSocket(runDir, "myplugin")
+	if err != nil {
+		t.Fatalf("discoverSocket() returned unexpected error: %s", err)
+	}
+	if want := filepath.Join(nestedDir, "myplugin.sock"); got != want {
+		t.Errorf("discoverSocket() = %q, want %q", got, want)
+	}
+}
+
+func TestDiscoverSocketDoesNotWalkDeeper(t *testing.T) {
+	runDir := t.TempDir()
+
+	// A socket nested two levels deep, under a directory that doesn't match
+	// the plugin name, must not be picked up: this is the Docker discovery
+	// bug discoverSocket deliberately avoids.
+	deepDir := filepath.Join(runDir, "unrelated", "myplugin")
+	if err := os.MkdirAll(deepDir, 0o755); err != nil {
+		t.Fatalf("while creating deep dir: %s", err)
+	}
+
+	l, err := net.Listen("unix", filepath.Join(deepDir, "myplugin.sock"))
+	if err != nil {
+		t.Fatalf("while creating test socket: %s", err)
+	}
+	defer l.Close()
+
+	if _, err := discoverSocket(runDir, "myplugin"); err == nil {
+		t.Error("discoverSocket() found a socket nested deeper than runDir/<name>/<name>.sock, want error")
+	}
+}
+
+func TestDiscoverSocketSpecFile(t *testing.T) {
+	runDir := t.TempDir()
+
+	addr := filepath.Join(runDir, "actual.sock")
+	l, err := net.Listen("unix", addr)
+	if err != nil {
+		t.Fatalf("while creating test socket: %s", err)
+	}
+	defer l.Close()
+
+	specPath := filepath.Join(runDir, "myplugin.json")
+	if err := os.WriteFile(specPath, []byte(`{"Addr":"`+addr+`"}`), 0o644); err != nil {
+		t.Fatalf("while writing spec file: %s", err)
+	}
+
+	got, err := discoverSocket(runDir, "myplugin")
+	if err != nil {
+		t.Fatalf("discoverSocket() returned unexpected error: %s", err)
+	}
+	if got != addr {
+		t.Errorf("discoverSocket() = %q, want %q", got, addr)
+	}
+}
+
+func TestDiscoverSocketNotFound(t *testing.T) {
+	runDir := t.TempDir()
+
+	if _, err := discoverSocket(runDir, "myplugin"); err == nil {
+		t.Error("discoverSocket() = nil error for a plugin with no socket, want error")
+	}
+}
+
+func TestIsSocket(t *testing.T) {
+	runDir := t.TempDir()
+
+	sockPath := filepath.Join(runDir, "a.sock")
+	l, err := net.Listen("unix", sockPath)
+	if err != nil {
+		t.Fatalf("while creating test socket: %s", err)
+	}
+	defer l.Close()
+
+	if !isSocket(sockPath) {
+		t.Errorf("isSocket(%q) = false, want true", sockPath)
+	}
+
+	regularPath := filepath.Join(runDir, "regular.txt")
+	if err := os.WriteFile(regularPath, []byte("not a socket"), 0o644); err != nil {
+		t.Fatalf("while writing regular file: %s", err)
+	}
+	if isSocket(regularPath) {
+		t.Errorf("isSocket(%q) = true, want false", regularPath)
+	}
+
+	if isSocket(filepath.Join(runDir, "missing")) {
+		t.Error("isSocket() = true for a missing path, want false")
+	}
+}
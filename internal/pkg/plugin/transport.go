@@ -0,0 +1,123 @@
+// Copyright (c) 2018, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package plugin
+
+import (
+	"fmt"
+	"net"
+	"net/rpc"
+	"path/filepath"
+	"plugin"
+	"time"
+
+	pluginapi "github.com/sylabs/singularity/pkg/plugin"
+)
+
+// Transport abstracts how a loaded plugin's exported symbols are reached:
+// either in the same process, via Go's plugin.Plugin, or out-of-process
+// over a Unix socket RPC connection. Meta.Start selects an implementation
+// based on Meta.Manifest.Runtime.
+type Transport interface {
+	// Start prepares the transport for use, e.g. opening a .so or dialing
+	// a socket.
+	Start() error
+	// Stop releases any resources held by the transport.
+	Stop() error
+	// HealthCheck reports whether the plugin is still responsive.
+	HealthCheck() error
+}
+
+// transportFor returns the Transport implementation selected by m's
+// manifest.
+func (m *Meta) transportFor(libexecdir string) Transport {
+	switch m.Manifest.Runtime {
+	case pluginapi.RuntimeSocket:
+		return &socketTransport{meta: m, runDir: filepath.Join(libexecdir, DirRoot, "run")}
+	default:
+		return &inProcessTransport{meta: m}
+	}
+}
+
+// inProcessTransport loads the plugin's object.so into the singularity
+// process itself using Go's plugin.Plugin. It requires the plugin to have
+// been built with the exact Go toolchain and module versions singularity
+// was, and cannot be unloaded without restarting singularity.
+type inProcessTransport struct {
+	meta *Meta
+}
+
+func (t *inProcessTransport) Start() error {
+	p, err := plugin.Open(t.meta.binaryName())
+	if err != nil {
+		return fmt.Errorf("while loading plugin object %s: %s", t.meta.binaryName(), err)
+	}
+	t.meta.binary = p
+	return nil
+}
+
+func (t *inProcessTransport) Stop() error {
+	return fmt.Errorf("in-process plugin %s cannot be unloaded without restarting singularity", t.meta.Name)
+}
+
+func (t *inProcessTransport) HealthCheck() error {
+	if t.meta.binary == nil {
+		return fmt.Errorf("plugin %s is not loaded", t.meta.Name)
+	}
+	return nil
+}
+
+// socketDialTimeout bounds how long socketTransport.Start waits to connect
+// to a plugin's socket.
+const socketDialTimeout = 5 * time.Second
+
+// socketTransport runs the plugin out-of-process, communicating with it
+// over an RPC connection to a Unix socket discovered under runDir
+// following Docker plugin discovery conventions. See discoverSocket.
+type socketTransport struct {
+	meta   *Meta
+	runDir string
+
+	conn   net.Conn
+	client *rpc.Client
+}
+
+func (t *socketTransport) Start() error {
+	addr, err := discoverSocket(t.runDir, t.meta.Name)
+	if err != nil {
+		return fmt.Errorf("while discovering socket for plugin %s: %s", t.meta.Name, err)
+	}
+
+	conn, err := net.DialTimeout("unix", addr, socketDialTimeout)
+	if err != nil {
+		return fmt.Errorf("while connecting to plugin %s at %s: %s", t.meta.Name, addr, err)
+	}
+
+	t.conn = conn
+	t.client = rpc.NewClient(conn)
+	return nil
+}
+
+func (t *socketTransport) Stop() error {
+	if t.client == nil {
+		return nil
+	}
+	return t.client.Close()
+}
+
+// HealthCheck calls the well-known Plugin.HealthCheck RPC method, which
+// every socket plugin is expected to implement.
+func (t *socketTransport) HealthCheck() error {
+	if t.client == nil {
+		return fmt.Errorf("plugin %s is not connected", t.meta.Name)
+	}
+
+	var reply struct{}
+	if err := t.client.Call("Plugin.HealthCheck", struct{}{}, &reply); err != nil {
+		return fmt.Errorf("plugin %s health check RPC failed: %s", t.meta.Name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,157 @@
+// Copyright (c) 2018, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package plugin
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sylabs/sif/pkg/sif"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/clearsign"
+)
+
+// errUnsigned is returned by verifySignature when fimg carries no
+// signature descriptors at all.
+var errUnsigned = fmt.Errorf("plugin SIF is not signed")
+
+// verifySignature checks that fimg carries a signature descriptor which
+// verifies against a public key in the keyring at keyringPath. keyringPath
+// is taken from Policy.Keyring, falling back to the default singularity
+// keyring if empty.
+func verifySignature(fimg *sif.FileImage, keyringPath string) error {
+	sigs := signatureDescriptors(fimg)
+	if len(sigs) == 0 {
+		return errUnsigned
+	}
+
+	keyring, err := loadKeyring(keyringPath)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for _, sig := range sigs {
+		if err := sig.verify(keyring); err != nil {
+			lastErr = err
+			continue
+		}
+		return nil
+	}
+
+	return fmt.Errorf("plugin SIF signature does not verify against the configured keyring: %s", lastErr)
+}
+
+// loadKeyring reads an armored PGP public keyring from path. An empty path
+// selects the default singularity keyring location.
+func loadKeyring(path string) (openpgp.EntityList, error) {
+	if path == "" {
+		path = defaultKeyringPath()
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("while opening keyring %s: %s", path, err)
+	}
+	defer f.Close()
+
+	keyring, err := openpgp.ReadArmoredKeyRing(f)
+	if err != nil {
+		return nil, fmt.Errorf("while reading keyring %s: %s", path, err)
+	}
+
+	return keyring, nil
+}
+
+// defaultKeyringPath returns the location of the singularity global public
+// keyring used to verify plugin signatures when no Policy.Keyring is set.
+func defaultKeyringPath() string {
+	return filepath.Join(os.Getenv("HOME"), ".singularity", "global-pgp-public")
+}
+
+// signatureDescriptor pairs the raw bytes of a signed data partition with
+// its signature descriptor, both read out of the plugin SIF. The signature
+// descriptor's bytes are not a detached signature over data itself: per
+// SIF's signing scheme, they are an armored, PGP clear-signed message whose
+// signed plaintext is a JSON digest naming the sha256 hash of the linked
+// descriptor's data.
+type signatureDescriptor struct {
+	data      []byte
+	signature []byte
+}
+
+// signedDigest is the JSON plaintext clear-signed inside a SIF signature
+// descriptor.
+type signedDigest struct {
+	Hash string `json:"hash"`
+}
+
+// verify checks that s.signature is a valid PGP clear-signed message under
+// keyring, and that the digest it signs matches the sha256 hash of s.data.
+// Both checks are required: the first confirms the signer is trusted, the
+// second confirms the signature actually covers the linked descriptor's
+// current contents rather than some other digest.
+func (s signatureDescriptor) verify(keyring openpgp.EntityList) error {
+	block, _ := clearsign.Decode(s.signature)
+	if block == nil {
+		return fmt.Errorf("signature descriptor does not contain a clear-signed PGP message")
+	}
+
+	if _, err := openpgp.CheckDetachedSignature(keyring, bytes.NewReader(block.Bytes), block.ArmoredSignature.Body); err != nil {
+		return err
+	}
+
+	var digest signedDigest
+	if err := json.Unmarshal(block.Bytes, &digest); err != nil {
+		return fmt.Errorf("while parsing signed digest: %s", err)
+	}
+
+	sum := sha256.Sum256(s.data)
+	want := hex.EncodeToString(sum[:])
+	if digest.Hash != want {
+		return fmt.Errorf("signed digest %s does not match linked descriptor's digest %s", digest.Hash, want)
+	}
+
+	return nil
+}
+
+// signatureDescriptors returns the signature descriptors present in fimg,
+// pairing each DataSignature descriptor with the data partition it signs,
+// found via the signature descriptor's Link.
+func signatureDescriptors(fimg *sif.FileImage) []signatureDescriptor {
+	var sigs []signatureDescriptor
+
+	for i := range fimg.DescrArr {
+		d := &fimg.DescrArr[i]
+		if !d.Used || d.Datatype != sif.DataSignature {
+			continue
+		}
+
+		signature, err := d.GetData(fimg)
+		if err != nil {
+			continue
+		}
+
+		signed, err := descriptorByID(fimg, d.Link)
+		if err != nil {
+			continue
+		}
+
+		data, err := signed.GetData(fimg)
+		if err != nil {
+			continue
+		}
+
+		sigs = append(sigs, signatureDescriptor{data: data, signature: signature})
+	}
+
+	return sigs
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2018, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package plugin
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/clearsign"
+)
+
+// signDigest builds a signatureDescriptor the way SIF itself would: an
+// armored, PGP clear-signed JSON digest of data, signed by signer.
+func signDigest(t *testing.T, signer *openpgp.Entity, data []byte) signatureDescriptor {
+	t.Helper()
+
+	sum := sha256.Sum256(data)
+	plaintext := fmt.Sprintf(`{"hash":%q}`, hex.EncodeToString(sum[:]))
+
+	var buf bytes.Buffer
+	w, err := clearsign.Encode(&buf, signer.PrivateKey, nil)
+	if err != nil {
+		t.Fatalf("while starting clearsign encoder: %s", err)
+	}
+	if _, err := w.Write([]byte(plaintext)); err != nil {
+		t.Fatalf("while writing clearsigned plaintext: %s", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("while closing clearsign encoder: %s", err)
+	}
+
+	return signatureDescriptor{data: data, signature: buf.Bytes()}
+}
+
+func testEntity(t *testing.T) *openpgp.Entity {
+	t.Helper()
+
+	entity, err := openpgp.NewEntity("Test Signer", "", "test@example.com", nil)
+	if err != nil {
+		t.Fatalf("while generating test PGP key: %s", err)
+	}
+	return entity
+}
+
+func TestSignatureDescriptorVerify(t *testing.T) {
+	signer := testEntity(t)
+	data := []byte("plugin partition contents")
+	sig := signDigest(t, signer, data)
+
+	if err := sig.verify(openpgp.EntityList{signer}); err != nil {
+		t.Errorf("verify() returned unexpected error for a validly signed descriptor: %s", err)
+	}
+}
+
+func TestSignatureDescriptorVerifyWrongKey(t *testing.T) {
+	signer := testEntity(t)
+	other := testEntity(t)
+	data := []byte("plugin partition contents")
+	sig := signDigest(t, signer, data)
+
+	if err := sig.verify(openpgp.EntityList{other}); err == nil {
+		t.Error("verify() = nil for a signature checked against a keyring that doesn't contain the signer, want error")
+	}
+}
+
+func TestSignatureDescriptorVerifyTamperedData(t *testing.T) {
+	signer := testEntity(t)
+	sig := signDigest(t, signer, []byte("original contents"))
+
+	// The linked descriptor's data no longer matches what was signed: the
+	// clear-signed digest still verifies as a PGP signature, but against
+	// the wrong hash.
+	sig.data = []byte("tampered contents")
+
+	if err := sig.verify(openpgp.EntityList{signer}); err == nil {
+		t.Error("verify() = nil when the linked descriptor's data doesn't match the signed digest, want error")
+	}
+}
+
+func TestSignatureDescriptorVerifyNotClearSigned(t *testing.T) {
+	signer := testEntity(t)
+	sig := signatureDescriptor{data: []byte("data"), signature: []byte("not a pgp message")}
+
+	if err := sig.verify(openpgp.EntityList{signer}); err == nil {
+		t.Error("verify() = nil for a signature that isn't a clear-signed PGP message, want error")
+	}
+}
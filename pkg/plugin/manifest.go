@@ -0,0 +1,33 @@
+// Copyright (c) 2018, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+// Package plugin defines the types shared between the singularity runtime
+// and the plugins it loads.
+package plugin
+
+// Manifest is the plugin's manifest, describing its identity to the
+// singularity runtime. It is stored as a Sifmanifest JSON descriptor inside
+// the plugin's SIF image.
+type Manifest struct {
+	// Name is the name of the plugin, as reported by the plugin itself.
+	Name string
+	// Description is a human readable description of the plugin.
+	Description string
+	// Author is the name of the plugin's author.
+	Author string
+	// Version is the plugin's own version string.
+	Version string
+	// Privileges lists the elevated capabilities the plugin requests of
+	// the host. An installer must obtain explicit acceptance of these
+	// before writing the plugin into the runtime's plugin directory.
+	Privileges Privileges `json:"privileges,omitempty"`
+	// Settings is the typed configuration schema the plugin declares.
+	// NewFromImage validates it and materializes its defaults into
+	// config.yaml at install time.
+	Settings Settings `json:"settings,omitempty"`
+	// Runtime selects how the plugin is executed once installed. The zero
+	// value behaves as RuntimeInProcess.
+	Runtime RuntimeKind `json:"runtime,omitempty"`
+}
@@ -0,0 +1,30 @@
+// Copyright (c) 2018, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package plugin
+
+// Privileges describes the elevated capabilities a plugin's manifest
+// requests of the host. It is surfaced to the caller before a plugin is
+// installed, analogous to the privilege prompt Docker shows before
+// installing a plugin, so that a user or automation can make an informed
+// decision about whether to grant them.
+type Privileges struct {
+	// Capabilities lists the Linux capabilities the plugin requests, e.g.
+	// "CAP_SYS_ADMIN".
+	Capabilities []string `json:"capabilities,omitempty"`
+	// Mounts lists host paths the plugin requests be bind mounted into its
+	// runtime environment.
+	Mounts []string `json:"mounts,omitempty"`
+	// Network reports whether the plugin requests host network access.
+	Network bool `json:"network,omitempty"`
+	// Setuid reports whether the plugin requests to run setuid-root.
+	Setuid bool `json:"setuid,omitempty"`
+}
+
+// None reports whether the manifest requests no elevated privileges at
+// all, in which case no prompt or --grant-all-privileges flag is required.
+func (p Privileges) None() bool {
+	return len(p.Capabilities) == 0 && len(p.Mounts) == 0 && !p.Network && !p.Setuid
+}
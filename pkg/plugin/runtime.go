@@ -0,0 +1,24 @@
+// Copyright (c) 2018, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package plugin
+
+// RuntimeKind selects how a plugin's code is executed once installed.
+type RuntimeKind string
+
+const (
+	// RuntimeInProcess loads the plugin's object.so into the singularity
+	// process itself via Go's plugin.Plugin. This is the historical
+	// behavior: it requires the plugin to be built with the exact Go
+	// toolchain and module versions singularity itself was built with,
+	// and it cannot be unloaded without restarting singularity. This is
+	// the default when a manifest does not declare Runtime.
+	RuntimeInProcess RuntimeKind = "inprocess"
+	// RuntimeSocket runs the plugin out-of-process, communicating with it
+	// over an RPC connection to a Unix socket. This relaxes the toolchain
+	// coupling of RuntimeInProcess and allows the plugin to be restarted
+	// or disabled independently of singularity.
+	RuntimeSocket RuntimeKind = "socket"
+)
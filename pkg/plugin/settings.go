@@ -0,0 +1,147 @@
+// Copyright (c) 2018, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package plugin
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+)
+
+// SettingType is the kind of value a Setting holds, mirroring Mattermost's
+// plugin settings model.
+type SettingType string
+
+const (
+	// SettingBool is a true/false toggle.
+	SettingBool SettingType = "bool"
+	// SettingText is a single-line string.
+	SettingText SettingType = "text"
+	// SettingLongText is a multi-line string.
+	SettingLongText SettingType = "longtext"
+	// SettingDropdown is a single choice from Setting.Options, rendered as
+	// a dropdown.
+	SettingDropdown SettingType = "dropdown"
+	// SettingRadio is a single choice from Setting.Options, rendered as a
+	// radio group.
+	SettingRadio SettingType = "radio"
+	// SettingGeneratedSecret is a string the installer generates using
+	// crypto/rand rather than asking the user for a value.
+	SettingGeneratedSecret SettingType = "generated"
+	// SettingUsername is a text field expected to hold a host username.
+	SettingUsername SettingType = "username"
+)
+
+// maxGeneratedLength bounds Setting.GeneratedLength to reject manifests
+// that request unreasonably large generated secrets.
+const maxGeneratedLength = 4096
+
+// SettingOption is one of the choices offered by a dropdown or radio
+// Setting.
+type SettingOption struct {
+	// DisplayName is shown to the user.
+	DisplayName string `json:"displayName"`
+	// Value is the string materialized into the config when this option
+	// is selected.
+	Value string `json:"value"`
+}
+
+// Setting is a single typed entry in a plugin's configuration schema.
+type Setting struct {
+	// Key is the name the value is stored under in config.yaml. Keys must
+	// be unique within a Settings schema.
+	Key string `json:"key"`
+	// DisplayName is shown to the user in place of Key.
+	DisplayName string `json:"displayName"`
+	// Type selects how the setting is presented and validated.
+	Type SettingType `json:"type"`
+	// Default is the value materialized for Bool, Text, LongText,
+	// Dropdown, Radio and Username settings. Ignored for
+	// SettingGeneratedSecret.
+	Default string `json:"default,omitempty"`
+	// Options lists the valid values for Dropdown and Radio settings.
+	Options []SettingOption `json:"options,omitempty"`
+	// GeneratedLength is the number of random bytes used to materialize a
+	// SettingGeneratedSecret, hex-encoded in the resulting config value.
+	GeneratedLength int `json:"generatedLength,omitempty"`
+}
+
+// Settings is the typed configuration schema declared by a plugin's
+// manifest.
+type Settings []Setting
+
+// Validate checks that Settings forms a well-formed schema: keys are
+// unique, dropdown/radio settings declare at least one option, and
+// generated-secret settings request a reasonable length.
+func (s Settings) Validate() error {
+	seen := make(map[string]bool, len(s))
+
+	for _, st := range s {
+		if seen[st.Key] {
+			return fmt.Errorf("duplicate plugin setting key %q", st.Key)
+		}
+		seen[st.Key] = true
+
+		switch st.Type {
+		case SettingDropdown, SettingRadio:
+			if len(st.Options) == 0 {
+				return fmt.Errorf("setting %q: %s requires at least one option", st.Key, st.Type)
+			}
+		case SettingGeneratedSecret:
+			if st.GeneratedLength <= 0 || st.GeneratedLength > maxGeneratedLength {
+				return fmt.Errorf("setting %q: unreasonable generated length %d", st.Key, st.GeneratedLength)
+			}
+		case SettingBool, SettingText, SettingLongText, SettingUsername:
+		default:
+			return fmt.Errorf("setting %q: unknown setting type %q", st.Key, st.Type)
+		}
+	}
+
+	return nil
+}
+
+// Defaults materializes a default value for every setting in s, generating
+// a fresh cryptographically random value for each SettingGeneratedSecret.
+// SettingBool materializes as a bool rather than a string, matching the
+// type ValidateConfig expects back from a round-tripped config.yaml.
+func (s Settings) Defaults() (map[string]interface{}, error) {
+	out := make(map[string]interface{}, len(s))
+
+	for _, st := range s {
+		switch st.Type {
+		case SettingGeneratedSecret:
+			v, err := randomHex(st.GeneratedLength)
+			if err != nil {
+				return nil, fmt.Errorf("while generating value for setting %q: %s", st.Key, err)
+			}
+			out[st.Key] = v
+		case SettingBool:
+			v := false
+			if st.Default != "" {
+				var err error
+				v, err = strconv.ParseBool(st.Default)
+				if err != nil {
+					return nil, fmt.Errorf("setting %q: default %q is not a valid bool: %s", st.Key, st.Default, err)
+				}
+			}
+			out[st.Key] = v
+		default:
+			out[st.Key] = st.Default
+		}
+	}
+
+	return out, nil
+}
+
+// randomHex returns n cryptographically random bytes, hex-encoded.
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,137 @@
+// Copyright (c) 2018, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package plugin
+
+import "testing"
+
+func TestSettingsValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings Settings
+		wantErr  bool
+	}{
+		{
+			name: "valid bool and text",
+			settings: Settings{
+				{Key: "enabled", Type: SettingBool},
+				{Key: "label", Type: SettingText},
+			},
+		},
+		{
+			name: "duplicate key",
+			settings: Settings{
+				{Key: "enabled", Type: SettingBool},
+				{Key: "enabled", Type: SettingText},
+			},
+			wantErr: true,
+		},
+		{
+			name: "dropdown without options",
+			settings: Settings{
+				{Key: "mode", Type: SettingDropdown},
+			},
+			wantErr: true,
+		},
+		{
+			name: "dropdown with options",
+			settings: Settings{
+				{Key: "mode", Type: SettingDropdown, Options: []SettingOption{{Value: "a"}, {Value: "b"}}},
+			},
+		},
+		{
+			name: "generated secret with unreasonable length",
+			settings: Settings{
+				{Key: "token", Type: SettingGeneratedSecret, GeneratedLength: maxGeneratedLength + 1},
+			},
+			wantErr: true,
+		},
+		{
+			name: "generated secret with zero length",
+			settings: Settings{
+				{Key: "token", Type: SettingGeneratedSecret, GeneratedLength: 0},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown type",
+			settings: Settings{
+				{Key: "mystery", Type: SettingType("unknown")},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.settings.Validate()
+			if tt.wantErr && err == nil {
+				t.Error("Validate() = nil, want error")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Validate() returned unexpected error: %s", err)
+			}
+		})
+	}
+}
+
+func TestSettingsDefaults(t *testing.T) {
+	settings := Settings{
+		{Key: "label", Type: SettingText, Default: "hello"},
+		{Key: "enabled", Type: SettingBool, Default: "true"},
+		{Key: "disabled", Type: SettingBool},
+		{Key: "secret", Type: SettingGeneratedSecret, GeneratedLength: 16},
+	}
+
+	defaults, err := settings.Defaults()
+	if err != nil {
+		t.Fatalf("Defaults() returned unexpected error: %s", err)
+	}
+
+	if defaults["label"] != "hello" {
+		t.Errorf("Defaults()[\"label\"] = %q, want %q", defaults["label"], "hello")
+	}
+
+	if v, ok := defaults["enabled"].(bool); !ok || !v {
+		t.Errorf("Defaults()[\"enabled\"] = %#v, want bool true", defaults["enabled"])
+	}
+	if v, ok := defaults["disabled"].(bool); !ok || v {
+		t.Errorf("Defaults()[\"disabled\"] = %#v, want bool false", defaults["disabled"])
+	}
+
+	secret, ok := defaults["secret"].(string)
+	if !ok || secret == "" {
+		t.Errorf("Defaults()[\"secret\"] = %#v, want a non-empty generated string", defaults["secret"])
+	}
+	if len(secret) != 32 { // hex-encoded 16 bytes
+		t.Errorf("Defaults()[\"secret\"] has length %d, want 32", len(secret))
+	}
+}
+
+func TestSettingsDefaultsInvalidBoolDefault(t *testing.T) {
+	settings := Settings{
+		{Key: "enabled", Type: SettingBool, Default: "not-a-bool"},
+	}
+
+	if _, err := settings.Defaults(); err == nil {
+		t.Error("Defaults() = nil error for an unparsable bool default, want error")
+	}
+}
+
+func TestSettingsDefaultsGeneratesDistinctSecrets(t *testing.T) {
+	settings := Settings{
+		{Key: "a", Type: SettingGeneratedSecret, GeneratedLength: 16},
+		{Key: "b", Type: SettingGeneratedSecret, GeneratedLength: 16},
+	}
+
+	defaults, err := settings.Defaults()
+	if err != nil {
+		t.Fatalf("Defaults() returned unexpected error: %s", err)
+	}
+
+	if defaults["a"] == defaults["b"] {
+		t.Error("Defaults() produced identical values for two independent generated secrets")
+	}
+}